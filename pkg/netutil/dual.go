@@ -0,0 +1,78 @@
+/*
+Copyright 2014 The Camlistore Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netutil
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+var (
+	tcp4Once    sync.Once
+	tcp4Listens bool
+	tcp6Once    sync.Once
+	tcp6Listens bool
+)
+
+// CanListenTCP4OnLoopback reports whether this machine can listen on the
+// IPv4 loopback address. The result is probed once and cached.
+func CanListenTCP4OnLoopback() bool {
+	tcp4Once.Do(func() {
+		l, err := net.Listen("tcp4", "127.0.0.1:0")
+		if err == nil {
+			l.Close()
+			tcp4Listens = true
+		}
+	})
+	return tcp4Listens
+}
+
+// CanListenTCP6OnLoopback reports whether this machine can listen on the
+// IPv6 loopback address. The result is probed once and cached.
+func CanListenTCP6OnLoopback() bool {
+	tcp6Once.Do(func() {
+		l, err := net.Listen("tcp6", "[::1]:0")
+		if err == nil {
+			l.Close()
+			tcp6Listens = true
+		}
+	})
+	return tcp6Listens
+}
+
+// ListenOnLocalRandomPortDual returns TCP listeners on random ports on both
+// the IPv4 and IPv6 loopback addresses. Either listener is nil if the
+// corresponding family isn't supported by the kernel/stack, as determined by
+// CanListenTCP4OnLoopback and CanListenTCP6OnLoopback. An error is returned
+// only if neither family is listenable.
+func ListenOnLocalRandomPortDual() (v4, v6 net.Listener, err error) {
+	if CanListenTCP4OnLoopback() {
+		if l, err := net.Listen("tcp4", "127.0.0.1:0"); err == nil {
+			v4 = l
+		}
+	}
+	if CanListenTCP6OnLoopback() {
+		if l, err := net.Listen("tcp6", "[::1]:0"); err == nil {
+			v6 = l
+		}
+	}
+	if v4 == nil && v6 == nil {
+		return nil, nil, errors.New("netutil: can't listen on either IPv4 or IPv6 loopback")
+	}
+	return v4, v6, nil
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2014 The Camlistore Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netutil
+
+import "testing"
+
+func TestListenOnLocalRandomPortDual(t *testing.T) {
+	v4, v6, err := ListenOnLocalRandomPortDual()
+	if err != nil {
+		t.Fatalf("ListenOnLocalRandomPortDual: %v", err)
+	}
+	if v4 != nil {
+		defer v4.Close()
+	}
+	if v6 != nil {
+		defer v6.Close()
+	}
+	if v4 == nil && v6 == nil {
+		t.Fatal("got no listeners for either family, but no error")
+	}
+	if CanListenTCP4OnLoopback() && v4 == nil {
+		t.Error("CanListenTCP4OnLoopback reported true but v4 listener is nil")
+	}
+	if CanListenTCP6OnLoopback() && v6 == nil {
+		t.Error("CanListenTCP6OnLoopback reported true but v6 listener is nil")
+	}
+}
+
+func TestCanListenProbesAreCached(t *testing.T) {
+	// The probes are cached via sync.Once; calling twice should be cheap
+	// and consistent.
+	a := CanListenTCP4OnLoopback()
+	b := CanListenTCP4OnLoopback()
+	if a != b {
+		t.Error("CanListenTCP4OnLoopback gave inconsistent results across calls")
+	}
+	ran := false
+	tcp4Once.Do(func() { ran = true })
+	if ran {
+		t.Error("CanListenTCP4OnLoopback did not cache its probe in tcp4Once")
+	}
+}
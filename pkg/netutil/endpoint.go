@@ -0,0 +1,137 @@
+/*
+Copyright 2014 The Camlistore Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netutil
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Endpoint describes where to reach a blob server: either a TCP address
+// (optionally wrapped in TLS) or a Unix domain socket. It's the structured
+// counterpart of HostPort, for configs that need to address a server over a
+// Unix socket instead of (or as well as) TCP.
+type Endpoint interface {
+	// Network returns the net.Dial-style network: "tcp" or "unix".
+	Network() string
+	// Address returns the net.Dial-style address for Network.
+	Address() string
+	// Dial connects to the endpoint, performing a TLS handshake first if
+	// the endpoint was parsed from a tcp+tls:// URL.
+	Dial(ctx context.Context) (net.Conn, error)
+	// Listen creates a listener for the endpoint, suitable for a server
+	// to accept connections on.
+	Listen() (net.Listener, error)
+}
+
+// ParseEndpoint parses urlStr, which should use one of the schemes "tcp",
+// "tcp+tls", or "unix", into an Endpoint. A "unix" URL addresses a socket
+// path via its URL path component, e.g. unix:///var/run/perkeep.sock. A
+// "tcp" or "tcp+tls" URL addresses a host:port, defaulting the port to 80
+// (tcp) or 443 (tcp+tls) if absent, the same way HostPort does for
+// http/https.
+func ParseEndpoint(urlStr string) (Endpoint, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %q as a url: %v", urlStr, err)
+	}
+	switch u.Scheme {
+	case "unix":
+		addr := u.Path
+		if addr == "" {
+			addr = u.Opaque
+		}
+		if addr == "" {
+			return nil, fmt.Errorf("url %q has no socket path", urlStr)
+		}
+		return unixEndpoint{addr: addr}, nil
+	case "tcp", "tcp+tls":
+		hostPort := u.Host
+		if hostPort == "" {
+			return nil, fmt.Errorf("url %q has no host", urlStr)
+		}
+		if !hasPort(hostPort) {
+			if u.Scheme == "tcp+tls" {
+				hostPort += ":443"
+			} else {
+				hostPort += ":80"
+			}
+		}
+		return tcpEndpoint{addr: hostPort, tls: u.Scheme == "tcp+tls"}, nil
+	default:
+		return nil, fmt.Errorf("url %q has unsupported scheme %q", urlStr, u.Scheme)
+	}
+}
+
+type tcpEndpoint struct {
+	addr string
+	tls  bool
+}
+
+func (e tcpEndpoint) Network() string { return "tcp" }
+func (e tcpEndpoint) Address() string { return e.addr }
+
+func (e tcpEndpoint) Dial(ctx context.Context) (net.Conn, error) {
+	var d net.Dialer
+	if !e.tls {
+		return d.DialContext(ctx, "tcp", e.addr)
+	}
+	c, err := d.DialContext(ctx, "tcp", e.addr)
+	if err != nil {
+		return nil, err
+	}
+	tc := tls.Client(c, &tls.Config{ServerName: tlsServerName(e.addr)})
+	if err := tc.HandshakeContext(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return tc, nil
+}
+
+func (e tcpEndpoint) Listen() (net.Listener, error) {
+	return net.Listen("tcp", e.addr)
+}
+
+type unixEndpoint struct {
+	addr string
+}
+
+func (e unixEndpoint) Network() string { return "unix" }
+func (e unixEndpoint) Address() string { return e.addr }
+
+func (e unixEndpoint) Dial(ctx context.Context) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", e.addr)
+}
+
+func (e unixEndpoint) Listen() (net.Listener, error) {
+	return net.Listen("unix", e.addr)
+}
+
+// hasPort reports whether hostPort has an explicit :port component,
+// handling bracketed IPv6 literals.
+func hasPort(hostPort string) bool {
+	idx := strings.Index(hostPort, "]")
+	if idx == -1 {
+		idx = 0
+	}
+	return strings.Contains(hostPort[idx:], ":")
+}
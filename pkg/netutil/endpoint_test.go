@@ -0,0 +1,133 @@
+/*
+Copyright 2014 The Camlistore Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantNet string
+		wantAdr string
+		wantErr bool
+	}{
+		{"unix:///var/run/perkeep.sock", "unix", "/var/run/perkeep.sock", false},
+		{"tcp://example.com:1234", "tcp", "example.com:1234", false},
+		{"tcp://example.com", "tcp", "example.com:80", false},
+		{"tcp+tls://example.com:1234", "tcp", "example.com:1234", false},
+		{"tcp+tls://example.com", "tcp", "example.com:443", false},
+		{"unix://", "", "", true},
+		{"ftp://example.com", "", "", true},
+		{"not a url with spaces and :// nonsense", "", "", true},
+	}
+	for _, tt := range tests {
+		ep, err := ParseEndpoint(tt.url)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseEndpoint(%q): got nil error, want error", tt.url)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseEndpoint(%q): unexpected error: %v", tt.url, err)
+			continue
+		}
+		if ep.Network() != tt.wantNet || ep.Address() != tt.wantAdr {
+			t.Errorf("ParseEndpoint(%q) = (%q, %q); want (%q, %q)",
+				tt.url, ep.Network(), ep.Address(), tt.wantNet, tt.wantAdr)
+		}
+	}
+}
+
+// TestTCPEndpointDialTLS verifies the tcp+tls Dial path sets ServerName on
+// the *tls.Config it hands to tls.Client, instead of passing nil (which
+// fails every handshake, regardless of the server's certificate, with "tls:
+// either ServerName or InsecureSkipVerify must be specified"). We dial a
+// listener presenting a self-signed cert for 127.0.0.1: the handshake still
+// fails since the cert isn't in a trusted root, but the failure must be a
+// certificate-verification error, proving ServerName made it into the
+// handshake at all.
+func TestTCPEndpointDialTLS(t *testing.T) {
+	cert := newLoopbackTestCert(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	tlsLn := tls.NewListener(ln, &tls.Config{Certificates: []tls.Certificate{cert}})
+
+	go func() {
+		c, err := tlsLn.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		c.(*tls.Conn).HandshakeContext(context.Background())
+	}()
+
+	ep, err := ParseEndpoint("tcp+tls://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ep.Dial(context.Background())
+	if err == nil {
+		t.Fatal("Dial: got nil error dialing a self-signed cert with no trusted root; want a certificate verification error")
+	}
+	var unknownAuth x509.UnknownAuthorityError
+	if !errors.As(err, &unknownAuth) {
+		t.Fatalf("Dial: got error %v (%T); want an x509.UnknownAuthorityError, proving ServerName was set instead of tls.Client failing on a nil config", err, err)
+	}
+}
+
+// newLoopbackTestCert returns a self-signed cert valid for 127.0.0.1, so that
+// Dial's ServerName (derived from the dialed host) verifies successfully.
+func newLoopbackTestCert(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2014 The Camlistore Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netutil
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultDebounce is how long a newly-observed IP set must remain stable,
+// across consecutive polls, before HostWatcher fires an event for it. This
+// absorbs brief flapping during e.g. a rolling DNS update.
+const defaultDebounce = 2
+
+// HostWatcher periodically resolves a hostname and reports when its set of
+// IP addresses changes. It's meant for long-lived clients (such as
+// pkg/client) that hold connections pinned to an IP and need to notice when
+// a remote's DNS record is repointed.
+type HostWatcher struct {
+	host     string
+	interval time.Duration
+	lookupIP func(host string) ([]net.IP, error) // overridden in tests
+
+	events   chan []net.IP
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewHostWatcher returns a HostWatcher that resolves host every interval and
+// starts watching immediately. Call Stop to release its resources.
+func NewHostWatcher(host string, interval time.Duration) *HostWatcher {
+	w := &HostWatcher{
+		host:     host,
+		interval: interval,
+		lookupIP: net.LookupIP,
+		events:   make(chan []net.IP, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go w.loop()
+	return w
+}
+
+// Events returns the channel on which HostWatcher sends the new sorted IP
+// set whenever a resolution is observed to have changed and settled.
+func (w *HostWatcher) Events() <-chan []net.IP {
+	return w.events
+}
+
+// Stop stops the watcher. It's safe to call Stop more than once, including
+// concurrently, same as context.CancelFunc.
+func (w *HostWatcher) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+}
+
+func (w *HostWatcher) loop() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	current := w.lookup()
+	var pending []net.IP
+	stableCount := 0
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+		got := w.lookup()
+		if sameIPSet(got, pending) {
+			stableCount++
+		} else {
+			pending = got
+			stableCount = 1
+		}
+		if stableCount >= defaultDebounce && !sameIPSet(got, current) {
+			current = got
+			select {
+			case w.events <- got:
+			case <-w.stop:
+				return
+			}
+		}
+	}
+}
+
+func (w *HostWatcher) lookup() []net.IP {
+	ips, err := w.lookupIP(w.host)
+	if err != nil {
+		return nil
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		return ips[i].String() < ips[j].String()
+	})
+	return ips
+}
+
+// sameIPSet reports whether a and b hold equal IPs in the same order. Since
+// lookup sorts its results, this compares two lookups as sets.
+func sameIPSet(a, b []net.IP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, ip := range a {
+		if !ip.Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
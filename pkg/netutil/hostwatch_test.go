@@ -0,0 +1,98 @@
+/*
+Copyright 2014 The Camlistore Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netutil
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLookup returns a lookupIP func that walks through sets on each call,
+// repeating the last one once exhausted.
+func fakeLookup(sets [][]net.IP) func(string) ([]net.IP, error) {
+	var mu sync.Mutex
+	i := 0
+	return func(string) ([]net.IP, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		s := sets[i]
+		if i < len(sets)-1 {
+			i++
+		}
+		return s, nil
+	}
+}
+
+func ips(ss ...string) []net.IP {
+	out := make([]net.IP, len(ss))
+	for i, s := range ss {
+		out[i] = net.ParseIP(s)
+	}
+	return out
+}
+
+func TestHostWatcherDebouncesFlap(t *testing.T) {
+	// Flap between two sets for a couple of polls, then settle on the new
+	// one; only the settled change should ever be reported.
+	w := &HostWatcher{
+		host:     "example.com",
+		interval: 5 * time.Millisecond,
+		lookupIP: fakeLookup([][]net.IP{
+			ips("10.0.0.1"),
+			ips("10.0.0.2"),
+			ips("10.0.0.1"),
+			ips("10.0.0.2"),
+			ips("10.0.0.2"),
+			ips("10.0.0.2"),
+		}),
+		events: make(chan []net.IP, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.loop()
+	defer w.Stop()
+
+	select {
+	case got := <-w.events:
+		if !sameIPSet(got, ips("10.0.0.2")) {
+			t.Fatalf("got event %v, want [10.0.0.2]", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for settled change event")
+	}
+
+	select {
+	case got := <-w.events:
+		t.Fatalf("got unexpected second event %v; flapping should have been debounced", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestHostWatcherStopIdempotentConcurrent(t *testing.T) {
+	w := NewHostWatcher("example.com", time.Hour)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Stop()
+		}()
+	}
+	wg.Wait()
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package netutil
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
@@ -27,22 +28,25 @@ import (
 
 // AwaitReachable tries to make a TCP connection to addr regularly.
 // It returns an error if it's unable to make a connection before maxWait.
+//
+// Deprecated: use AwaitReachableCtx, which supports cancellation, backoff,
+// and probe modes beyond a raw TCP dial.
 func AwaitReachable(addr string, maxWait time.Duration) error {
-	done := time.Now().Add(maxWait)
-	for time.Now().Before(done) {
-		c, err := net.Dial("tcp", addr)
-		if err == nil {
-			c.Close()
-			return nil
-		}
-		time.Sleep(100 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), maxWait)
+	defer cancel()
+	if err := AwaitReachableCtx(ctx, addr); err != nil {
+		return fmt.Errorf("%v unreachable for %v: %w", addr, maxWait, err)
 	}
-	return fmt.Errorf("%v unreachable for %v", addr, maxWait)
+	return nil
 }
 
 // HostPort takes a urlStr string URL, and returns a host:port string suitable
 // to passing to net.Dial, with the port set as the scheme's default port if
 // absent.
+//
+// HostPort treats every URL as a TCP address: the port defaults to 443 for
+// https and to 80 for any other scheme. For configs that may
+// also need to address a Unix domain socket, use ParseEndpoint instead.
 func HostPort(urlStr string) (string, error) {
 	u, err := url.Parse(urlStr)
 	if err != nil {
@@ -55,11 +59,7 @@ func HostPort(urlStr string) (string, error) {
 	if hostPort == "" || strings.HasPrefix(hostPort, ":") {
 		return "", fmt.Errorf("url %q has no host", urlStr)
 	}
-	idx := strings.Index(hostPort, "]")
-	if idx == -1 {
-		idx = 0
-	}
-	if !strings.Contains(hostPort[idx:], ":") {
+	if !hasPort(hostPort) {
 		if u.Scheme == "https" {
 			hostPort += ":443"
 		} else {
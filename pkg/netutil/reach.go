@@ -0,0 +1,206 @@
+/*
+Copyright 2014 The Camlistore Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netutil
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// probeMode selects how AwaitReachableCtx decides that addr is up.
+type probeMode int
+
+const (
+	probeTCP probeMode = iota
+	probeTLS
+	probeHTTP
+)
+
+// reachOpts holds the accumulated configuration from a list of ReachOptions.
+type reachOpts struct {
+	mode           probeMode
+	path           string // request path, for probeHTTP
+	useTLS         bool   // for probeHTTP: dial with TLS (https)
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	onAttempt      func(err error)
+}
+
+// ReachOption configures the probing behavior of AwaitReachableCtx.
+type ReachOption func(*reachOpts)
+
+// WithTLS makes AwaitReachableCtx probe addr by performing a TLS handshake,
+// which also confirms that a certificate is being served.
+func WithTLS() ReachOption {
+	return func(o *reachOpts) { o.mode = probeTLS }
+}
+
+// WithHTTP makes AwaitReachableCtx probe addr with an HTTP GET to path,
+// considering any 2xx or 3xx response as reachable.
+func WithHTTP(path string) ReachOption {
+	return func(o *reachOpts) {
+		o.mode = probeHTTP
+		o.path = path
+		o.useTLS = false
+	}
+}
+
+// WithHTTPS is like WithHTTP, but issues the GET over TLS.
+func WithHTTPS(path string) ReachOption {
+	return func(o *reachOpts) {
+		o.mode = probeHTTP
+		o.path = path
+		o.useTLS = true
+	}
+}
+
+// WithBackoff overrides the default exponential backoff bounds between
+// probe attempts. Actual delays are jittered within [current/2, current).
+func WithBackoff(initial, max time.Duration) ReachOption {
+	return func(o *reachOpts) {
+		o.initialBackoff = initial
+		o.maxBackoff = max
+	}
+}
+
+// WithOnAttempt registers a hook that's called with the error (or nil, on
+// success) of every probe attempt, letting the caller log progress.
+func WithOnAttempt(fn func(err error)) ReachOption {
+	return func(o *reachOpts) { o.onAttempt = fn }
+}
+
+// AwaitReachableCtx tries to reach addr, retrying with exponential backoff
+// and jitter until a probe succeeds or ctx is done; with a ctx that is never
+// done it retries indefinitely. By default it probes with a plain TCP dial;
+// use WithTLS, WithHTTP, or WithHTTPS to change what "reachable" means.
+//
+// If ctx is done before addr becomes reachable, it returns an error that
+// wraps ctx.Err() and includes the text of the last probe attempt's error.
+func AwaitReachableCtx(ctx context.Context, addr string, opts ...ReachOption) error {
+	o := reachOpts{
+		initialBackoff: 50 * time.Millisecond,
+		maxBackoff:     2 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	backoff := o.initialBackoff
+	var lastErr error
+	for {
+		lastErr = probe(ctx, addr, o)
+		if o.onAttempt != nil {
+			o.onAttempt(lastErr)
+		}
+		if lastErr == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%v unreachable: %w (last probe error: %v)", addr, ctx.Err(), lastErr)
+		case <-time.After(jitter(backoff)):
+		}
+		backoff *= 2
+		if backoff > o.maxBackoff {
+			backoff = o.maxBackoff
+		}
+	}
+}
+
+// jitter returns a random duration in [d/2, d).
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d-d/2)))
+}
+
+func probe(ctx context.Context, addr string, o reachOpts) error {
+	switch o.mode {
+	case probeTLS:
+		return probeTLSHandshake(ctx, addr)
+	case probeHTTP:
+		return probeHTTPGet(ctx, addr, o.path, o.useTLS)
+	default:
+		return probeTCPDial(ctx, addr)
+	}
+}
+
+func probeTCPDial(ctx context.Context, addr string) error {
+	var d net.Dialer
+	c, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return c.Close()
+}
+
+func probeTLSHandshake(ctx context.Context, addr string) error {
+	var d net.Dialer
+	c, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	tc := tls.Client(c, &tls.Config{ServerName: tlsServerName(addr)})
+	if err := tc.HandshakeContext(ctx); err != nil {
+		c.Close()
+		return err
+	}
+	return tc.Close()
+}
+
+// tlsServerName extracts the host part of addr for use as a tls.Config's
+// ServerName, the same way tls.DialWithDialer does internally.
+func tlsServerName(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+func probeHTTPGet(ctx context.Context, addr, path string, useTLS bool) error {
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	if path == "" {
+		path = "/"
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", scheme+"://"+addr+path, nil)
+	if err != nil {
+		return err
+	}
+	// Redirects are not followed, so a 3xx from path itself counts as reachable.
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("got HTTP status %s", resp.Status)
+	}
+	return nil
+}
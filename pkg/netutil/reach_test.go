@@ -0,0 +1,105 @@
+/*
+Copyright 2014 The Camlistore Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netutil
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAwaitReachableCtxSucceeds(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := AwaitReachableCtx(ctx, ln.Addr().String()); err != nil {
+		t.Fatalf("AwaitReachableCtx: %v", err)
+	}
+}
+
+func TestAwaitReachableCtxCancellation(t *testing.T) {
+	// Nothing is listening on this address, so every probe fails and we
+	// expect AwaitReachableCtx to give up promptly once ctx is done.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = AwaitReachableCtx(ctx, addr, WithBackoff(10*time.Millisecond, 20*time.Millisecond))
+	elapsed := time.Since(start)
+	if err == nil {
+		t.Fatal("AwaitReachableCtx: got nil error dialing a closed port; want an error")
+	}
+	if elapsed > 2*time.Second {
+		t.Fatalf("AwaitReachableCtx took %v to return after ctx expired; backoff should not have stalled shutdown this long", elapsed)
+	}
+}
+
+func TestAwaitReachableCtxOnAttempt(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+	var attempts int
+	AwaitReachableCtx(ctx, addr, WithBackoff(10*time.Millisecond, 10*time.Millisecond), WithOnAttempt(func(err error) {
+		attempts++
+		if err == nil {
+			t.Error("onAttempt called with nil error for a closed port")
+		}
+	}))
+	if attempts == 0 {
+		t.Error("onAttempt was never called")
+	}
+}
+
+func TestJitterBounds(t *testing.T) {
+	d := 100 * time.Millisecond
+	for i := 0; i < 1000; i++ {
+		j := jitter(d)
+		if j < d/2 || j >= d {
+			t.Fatalf("jitter(%v) = %v, want in [%v, %v)", d, j, d/2, d)
+		}
+	}
+	if got := jitter(0); got != 0 {
+		t.Errorf("jitter(0) = %v, want 0", got)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2014 The Camlistore Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netutil
+
+import (
+	"fmt"
+	"net"
+)
+
+// RoutedInterface returns a non-loopback interface that's up, filtered by
+// flags. It's used to pick the interface to advertise as this machine's
+// externally-reachable address. network must be "ip", "ip4", or "ip6".
+func RoutedInterface(network string, flags net.Flags) (*net.Interface, error) {
+	switch network {
+	case "ip", "ip4", "ip6":
+	default:
+		return nil, fmt.Errorf("netutil: invalid network %q", network)
+	}
+	ift, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, ifi := range ift {
+		if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagLoopback != 0 || ifi.Flags&flags != flags {
+			continue
+		}
+		if _, ok := hasRoutableAddr(&ifi, network); ok {
+			return &ifi, nil
+		}
+	}
+	return nil, fmt.Errorf("netutil: no routed interface found for network %q", network)
+}
+
+// RoutableIP returns a non-loopback IP address for the given network ("ip",
+// "ip4", or "ip6") on the interface returned by RoutedInterface. It prefers a
+// global unicast address and otherwise returns a link-local unicast one,
+// which is not routable beyond the local link; other interfaces are not
+// searched.
+func RoutableIP(network string) (net.IP, error) {
+	ifi, err := RoutedInterface(network, net.FlagUp)
+	if err != nil {
+		return nil, err
+	}
+	ip, ok := hasRoutableAddr(ifi, network)
+	if !ok {
+		return nil, fmt.Errorf("netutil: no routable address found on interface %s", ifi.Name)
+	}
+	return ip, nil
+}
+
+// hasRoutableAddr reports whether ifi has an address usable for network,
+// returning the best one found (preferring global unicast over link-local).
+func hasRoutableAddr(ifi *net.Interface, network string) (net.IP, bool) {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, false
+	}
+	var fallback net.IP
+	for _, addr := range addrs {
+		var ip net.IP
+		switch v := addr.(type) {
+		case *net.IPNet:
+			ip = v.IP
+		case *net.IPAddr:
+			ip = v.IP
+		}
+		if ip == nil || ip.IsLoopback() || ip.IsLinkLocalMulticast() {
+			continue
+		}
+		switch network {
+		case "ip4":
+			if ip.To4() == nil {
+				continue
+			}
+		case "ip6":
+			if ip.To4() != nil {
+				continue
+			}
+		}
+		if ip.IsGlobalUnicast() {
+			return ip, true
+		}
+		if ip.IsLinkLocalUnicast() && fallback == nil {
+			fallback = ip
+		}
+	}
+	if fallback != nil {
+		return fallback, true
+	}
+	return nil, false
+}
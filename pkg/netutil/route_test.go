@@ -0,0 +1,38 @@
+/*
+Copyright 2014 The Camlistore Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netutil
+
+import "testing"
+
+func TestRoutedInterfaceInvalidNetwork(t *testing.T) {
+	if _, err := RoutedInterface("ip5", 0); err == nil {
+		t.Error(`RoutedInterface("ip5", 0): got nil error, want error for invalid network`)
+	}
+}
+
+// TestRoutableIP is a smoke test: it only requires that, on a machine with
+// at least one non-loopback interface (true of any CI runner or dev box),
+// RoutableIP finds something and it isn't a loopback address.
+func TestRoutableIP(t *testing.T) {
+	ip, err := RoutableIP("ip")
+	if err != nil {
+		t.Skipf("no routable interface on this host: %v", err)
+	}
+	if ip.IsLoopback() {
+		t.Errorf("RoutableIP returned loopback address %v", ip)
+	}
+}